@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/strvals"
+	"sigs.k8s.io/kubectl-validate/pkg/utils"
+	"sigs.k8s.io/kubectl-validate/pkg/validatorfactory"
+)
+
+// isHelmChartDir returns true if path is a directory containing a Helm
+// chart, identified by the presence of a Chart.yaml alongside a templates/
+// directory.
+func isHelmChartDir(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(path, "templates"))
+	return err == nil
+}
+
+// renderHelmChart renders the Helm chart rooted at chartPath using valuesFiles
+// (applied in order via -f/--values) and setValues (applied last, like
+// --set key=val), and returns the rendered manifests keyed by the
+// chart-relative template path (e.g. "templates/deployment.yaml") so callers
+// can attribute validation errors back to the template that produced them.
+func renderHelmChart(chartPath string, valuesFiles []string, setValues []string) (map[string][]byte, error) {
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helm chart %q: %w", chartPath, err)
+	}
+
+	vals := map[string]interface{}{}
+	for _, f := range valuesFiles {
+		overrides, err := chartutil.ReadValuesFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file %q: %w", f, err)
+		}
+		vals = chartutil.CoalesceTables(overrides, vals)
+	}
+	for _, set := range setValues {
+		if err := strvalsParseInto(set, vals); err != nil {
+			return nil, fmt.Errorf("failed to parse --set %q: %w", set, err)
+		}
+	}
+
+	releaseOptions := chartutil.ReleaseOptions{
+		Name:      chart.Name(),
+		Namespace: "default",
+		IsInstall: true,
+	}
+	capabilities := chartutil.DefaultCapabilities
+	renderValues, err := chartutil.ToRenderValues(chart, vals, releaseOptions, capabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute render values: %w", err)
+	}
+
+	rendered, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart templates: %w", err)
+	}
+
+	out := make(map[string][]byte, len(rendered))
+	for name, manifest := range rendered {
+		if strings.TrimSpace(manifest) == "" || !isRenderedManifest(name) {
+			continue
+		}
+		// engine.Render always keys results "<chart>/templates/<file>" with a
+		// literal "/", regardless of OS; trim the chart name prefix so errors
+		// are reported relative to chartPath, matching how the rest of the
+		// tool reports paths.
+		relName := strings.TrimPrefix(name, chart.Name()+"/")
+		out[relName] = []byte(manifest)
+	}
+	return out, nil
+}
+
+// isRenderedManifest reports whether a path from engine.Render's output is a
+// manifest that should be validated, excluding the README-style
+// templates/NOTES.txt every `helm create`-scaffolded chart carries and any
+// other non-YAML/JSON output (e.g. a rendered ConfigMap data file), the same
+// way `helm template` itself skips them.
+func isRenderedManifest(name string) bool {
+	if filepath.Base(name) == "NOTES.txt" {
+		return false
+	}
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// strvalsParseInto parses a single --set expression and merges it into dest,
+// following the same dotted-path/list-index syntax as Helm's strvals package.
+func strvalsParseInto(set string, dest map[string]interface{}) error {
+	return strvals.ParseInto(set, dest)
+}
+
+// validateHelmChart renders chartPath and validates every resulting manifest,
+// returning errors attributed to the template-relative path so they read the
+// same as a directory of plain YAML files passed to ValidateFile.
+func validateHelmChart(chartPath string, valuesFiles []string, setValues []string, factory *validatorfactory.ValidatorFactory, policies *policySet) (map[string][]error, error) {
+	rendered, err := renderHelmChart(chartPath, valuesFiles, setValues)
+	if err != nil {
+		return nil, err
+	}
+
+	filesErrors := make(map[string][]error, len(rendered))
+	for templatePath, manifest := range rendered {
+		attributedPath := filepath.Join(chartPath, templatePath)
+		documents, err := utils.SplitYamlDocuments(manifest)
+		if err != nil {
+			filesErrors[attributedPath] = []error{err}
+			continue
+		}
+		var errs []error
+		for _, document := range documents {
+			if utils.IsEmptyYamlDocument(document) {
+				errs = append(errs, nil)
+			} else {
+				errs = append(errs, ValidateDocumentWithPolicies(document, factory, policies))
+			}
+		}
+		filesErrors[attributedPath] = errs
+	}
+	return filesErrors, nil
+}
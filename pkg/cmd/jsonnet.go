@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	jsonnet "github.com/google/go-jsonnet"
+	"sigs.k8s.io/kubectl-validate/pkg/utils"
+	"sigs.k8s.io/kubectl-validate/pkg/validatorfactory"
+	"sigs.k8s.io/yaml"
+)
+
+// isJsonnetFile reports whether path should be evaluated as Jsonnet before
+// validation, rather than parsed directly as YAML/JSON.
+func isJsonnetFile(path string) bool {
+	return strings.HasSuffix(path, ".jsonnet") || strings.HasSuffix(path, ".libsonnet")
+}
+
+// newJsonnetVM builds a go-jsonnet VM with the given library search paths
+// and a small set of native functions mirroring kubecfg's, so charts
+// written against that convention evaluate unmodified.
+func newJsonnetVM(jpath []string) *jsonnet.VM {
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.FileImporter{JPaths: jpath})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "parseJson",
+		Params: []jsonnet.Identifier{"json"},
+		Func: func(args []interface{}) (interface{}, error) {
+			var out interface{}
+			if err := json.Unmarshal([]byte(args[0].(string)), &out); err != nil {
+				return nil, err
+			}
+			return out, nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "parseYaml",
+		Params: []jsonnet.Identifier{"yaml"},
+		Func: func(args []interface{}) (interface{}, error) {
+			documents, err := utils.SplitYamlDocuments([]byte(args[0].(string)))
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, 0, len(documents))
+			for _, document := range documents {
+				var parsed interface{}
+				if err := yaml.Unmarshal(document, &parsed); err != nil {
+					return nil, err
+				}
+				out = append(out, parsed)
+			}
+			return out, nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "manifestJson",
+		Params: []jsonnet.Identifier{"json"},
+		Func: func(args []interface{}) (interface{}, error) {
+			data, err := json.MarshalIndent(args[0], "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "escapeStringRegex",
+		Params: []jsonnet.Identifier{"str"},
+		Func: func(args []interface{}) (interface{}, error) {
+			return regexp.QuoteMeta(args[0].(string)), nil
+		},
+	})
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "regexMatch",
+		Params: []jsonnet.Identifier{"regex", "string"},
+		Func: func(args []interface{}) (interface{}, error) {
+			return regexp.MatchString(args[0].(string), args[1].(string))
+		},
+	})
+	return vm
+}
+
+// evalJsonnetFile evaluates the Jsonnet file at path and flattens the
+// result into documents ready for ValidateDocument. The top-level value may
+// be a single manifest object, an array of manifests, or a map of name ->
+// manifest object (kubecfg's common "named objects" convention); each
+// resulting document is returned alongside a synthetic sub-path such as
+// "foo.jsonnet#/deployment" so errors are attributed precisely.
+func evalJsonnetFile(path string, jpath []string) (map[string][]byte, error) {
+	vm := newJsonnetVM(jpath)
+	jsonStr, err := vm.EvaluateFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate jsonnet file %q: %w", path, err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &value); err != nil {
+		return nil, fmt.Errorf("failed to parse jsonnet output for %q: %w", path, err)
+	}
+
+	out := map[string][]byte{}
+	switch v := value.(type) {
+	case []interface{}:
+		for i, doc := range v {
+			data, err := json.Marshal(doc)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprintf("%s#/%d", path, i)] = data
+		}
+	case map[string]interface{}:
+		if looksLikeManifest(v) {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			out[path] = data
+		} else {
+			for name, doc := range v {
+				data, err := json.Marshal(doc)
+				if err != nil {
+					return nil, err
+				}
+				out[fmt.Sprintf("%s#/%s", path, name)] = data
+			}
+		}
+	default:
+		return nil, fmt.Errorf("jsonnet file %q did not evaluate to an object, array, or map of objects", path)
+	}
+	return out, nil
+}
+
+// validateJsonnetFile evaluates path as Jsonnet and validates every manifest
+// it produces, writing results into result keyed by the synthetic sub-path
+// evalJsonnetFile assigns each one. It is called both for .jsonnet/.libsonnet
+// arguments given directly and for such files discovered inside a directory
+// argument, so either form of input is evaluated rather than parsed as YAML.
+func validateJsonnetFile(path string, jpath []string, factory *validatorfactory.ValidatorFactory, policies *policySet, result map[string][]error) {
+	documents, err := evalJsonnetFile(path, jpath)
+	if err != nil {
+		result[path] = []error{err}
+		return
+	}
+	for subPath, document := range documents {
+		result[subPath] = []error{ValidateDocumentWithPolicies(document, factory, policies)}
+	}
+}
+
+// looksLikeManifest distinguishes a single Kubernetes manifest from a
+// kubecfg-style map-of-manifests by checking for the apiVersion/kind keys
+// every manifest must carry.
+func looksLikeManifest(v map[string]interface{}) bool {
+	_, hasAPIVersion := v["apiVersion"]
+	_, hasKind := v["kind"]
+	return hasAPIVersion && hasKind
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"sigs.k8s.io/kubectl-validate/pkg/utils"
+	"sigs.k8s.io/kubectl-validate/pkg/validatorfactory"
+	"sigs.k8s.io/yaml"
+)
+
+// originAnnotation is set by kustomize when built with
+// `buildmetadata: [originAnnotations]`, and records the base/overlay file
+// that produced a given resource.
+const originAnnotation = "config.kubernetes.io/origin"
+
+// isKustomizeDir returns true if path is a directory containing a kustomize
+// overlay, identified by a kustomization.yaml/yml/Kustomization file.
+func isKustomizeDir(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	for _, name := range []string{"kustomization.yaml", "kustomization.yml", "Kustomization"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateKustomizeOverlay builds the overlay rooted at overlayPath in-memory
+// and validates every emitted resource, attributing errors back to the
+// resource's GVK, name, and (when available via the origin annotation) the
+// base/overlay file that produced it.
+func validateKustomizeOverlay(overlayPath string, factory *validatorfactory.ValidatorFactory, policies *policySet) (map[string][]error, error) {
+	opts := krusty.MakeDefaultOptions()
+	opts.AddManagedbyLabel = false
+	opts.PluginConfig.HelmConfig.Enabled = false
+	k := krusty.MakeKustomizer(opts)
+
+	resMap, err := k.Run(filesys.MakeFsOnDisk(), overlayPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomize overlay %q: %w", overlayPath, err)
+	}
+
+	filesErrors := make(map[string][]error)
+	for _, res := range resMap.Resources() {
+		document, err := res.AsYAML()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render built resource: %w", err)
+		}
+
+		path := resourceOrigin(res, overlayPath)
+		if utils.IsEmptyYamlDocument(document) {
+			filesErrors[path] = append(filesErrors[path], nil)
+			continue
+		}
+		filesErrors[path] = append(filesErrors[path], ValidateDocumentWithPolicies(document, factory, policies))
+	}
+	return filesErrors, nil
+}
+
+// resourceOrigin derives the path used to attribute a built resource's
+// errors: the origin annotation's path when present (so base/overlay
+// mismatches are traceable), falling back to a synthetic
+// "<overlay>#<gvk>/<namespace>/<name>" identifier otherwise.
+func resourceOrigin(res *resource.Resource, overlayPath string) string {
+	if raw, ok := res.GetAnnotations()[originAnnotation]; ok && raw != "" {
+		// The annotation value is a small YAML document such as
+		// "path: base/deployment.yaml"; only its path field is used.
+		var origin struct {
+			Path string `json:"path"`
+		}
+		if err := yaml.Unmarshal([]byte(raw), &origin); err == nil && origin.Path != "" {
+			return filepath.Join(overlayPath, origin.Path)
+		}
+	}
+	gvk := res.GetGvk()
+	return fmt.Sprintf("%s#%s/%s/%s", overlayPath, gvk.String(), res.GetNamespace(), res.GetName())
+}
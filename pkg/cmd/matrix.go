@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+
+	"sigs.k8s.io/kubectl-validate/pkg/openapiclient"
+	"sigs.k8s.io/kubectl-validate/pkg/utils"
+	"sigs.k8s.io/kubectl-validate/pkg/validatorfactory"
+	"sigs.k8s.io/yaml"
+)
+
+// maxConcurrentTargets bounds how many validation targets run in parallel,
+// so a large --matrix doesn't open unbounded connections to every cluster
+// at once.
+const maxConcurrentTargets = 4
+
+// validationTarget is one named combination of Kubernetes version, kube
+// context, and local CRD directory to validate every input manifest
+// against.
+type validationTarget struct {
+	Name         string `json:"name"`
+	Version      string `json:"version,omitempty"`
+	KubeContext  string `json:"kubeContext,omitempty"`
+	LocalCRDsDir string `json:"localCRDs,omitempty"`
+}
+
+// matrixConfig is the shape of the file passed via --matrix: a plain list
+// of named targets.
+type matrixConfig struct {
+	Targets []validationTarget `json:"targets"`
+}
+
+// buildTargets resolves the set of validationTargets implied by c's flags:
+// either the contents of --matrix, or the (possibly repeated) --versions /
+// --kube-contexts / --local-crds-matrix values zipped together positionally,
+// with --version and --local-crds as fallbacks. A single unnamed target is
+// named "default" so single-cluster runs read the same as before this
+// feature existed.
+func buildTargets(c *commandFlags) ([]validationTarget, error) {
+	if c.matrixFile != "" {
+		raw, err := os.ReadFile(c.matrixFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --matrix file %q: %w", c.matrixFile, err)
+		}
+		var cfg matrixConfig
+		if err := yaml.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse --matrix file %q: %w", c.matrixFile, err)
+		}
+		if len(cfg.Targets) == 0 {
+			return nil, fmt.Errorf("--matrix file %q defines no targets", c.matrixFile)
+		}
+		return dedupTargetNames(cfg.Targets), nil
+	}
+
+	count := len(c.versions)
+	if len(c.kubeContexts) > count {
+		count = len(c.kubeContexts)
+	}
+	if len(c.localCRDsDirs) > count {
+		count = len(c.localCRDsDirs)
+	}
+	if count <= 1 {
+		return []validationTarget{{
+			Name:         "default",
+			Version:      stringAt(c.versions, 0, c.version),
+			KubeContext:  stringAt(c.kubeContexts, 0, ""),
+			LocalCRDsDir: stringAt(c.localCRDsDirs, 0, c.localCRDsDir),
+		}}, nil
+	}
+
+	targets := make([]validationTarget, count)
+	for i := range targets {
+		targets[i] = validationTarget{
+			Version:      stringAt(c.versions, i, c.version),
+			KubeContext:  stringAt(c.kubeContexts, i, ""),
+			LocalCRDsDir: stringAt(c.localCRDsDirs, i, c.localCRDsDir),
+			Name:         fmt.Sprintf("%s@%s", stringAt(c.versions, i, c.version), nonEmpty(stringAt(c.kubeContexts, i, ""), "local")),
+		}
+	}
+	// version+context alone doesn't account for --local-crds-matrix, so two
+	// targets can otherwise share a name and silently overwrite each other's
+	// results; disambiguate on collision.
+	return dedupTargetNames(targets), nil
+}
+
+// dedupTargetNames assigns every unnamed target a name and disambiguates any
+// collisions (including between two unnamed or two explicitly duplicated
+// names) by appending "#N", so two targets can never silently overwrite each
+// other's results in a FilesErrors keyed by target name.
+func dedupTargetNames(targets []validationTarget) []validationTarget {
+	seenNames := make(map[string]int, len(targets))
+	for i := range targets {
+		base := nonEmpty(targets[i].Name, fmt.Sprintf("target-%d", i+1))
+		name := base
+		for n := 2; seenNames[name] > 0; n++ {
+			name = fmt.Sprintf("%s#%d", base, n)
+		}
+		seenNames[name] = 1
+		targets[i].Name = name
+	}
+	return targets
+}
+
+func stringAt(values []string, i int, fallback string) string {
+	if i < len(values) {
+		return values[i]
+	}
+	if len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return fallback
+}
+
+func nonEmpty(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
+
+// buildFactory constructs the ValidatorFactory for a single validationTarget,
+// following the same OpenAPI-source priority order as before this feature
+// existed: user patches, local schemas/CRDs, then cluster/hardcoded/GitHub
+// builtins for target.Version.
+func buildFactory(target validationTarget, c *commandFlags) (*validatorfactory.ValidatorFactory, error) {
+	overrides := c.kubeConfigOverrides
+	if target.KubeContext != "" {
+		overrides.CurrentContext = target.KubeContext
+	}
+
+	return validatorfactory.New(
+		openapiclient.NewOverlay(
+			openapiclient.PatchLoaderFromDirectory(nil, c.schemaPatchesDir),
+			openapiclient.NewComposite(
+				openapiclient.NewLocalSchemaFiles(nil, c.localSchemasDir),
+				openapiclient.NewLocalCRDFiles(nil, target.LocalCRDsDir),
+				openapiclient.NewOverlay(
+					openapiclient.HardcodedPatchLoader(target.Version),
+					openapiclient.NewFallback(
+						openapiclient.NewKubeConfig(overrides),
+						openapiclient.NewFallback(
+							openapiclient.NewHardcodedBuiltins(target.Version),
+							openapiclient.NewGitHubBuiltins(target.Version),
+						)),
+				),
+			),
+		),
+	)
+}
+
+// validateAgainstTarget runs the full single-target validation pipeline
+// (Helm/kustomize/jsonnet detection, plain file discovery, optional
+// server-dry-run) for one validationTarget and returns a flat path -> errors
+// map, the same shape the tool produced before multi-target support existed.
+func validateAgainstTarget(ctx context.Context, c *commandFlags, args []string, target validationTarget, policies *policySet) (map[string][]error, error) {
+	factory, err := buildFactory(target, c)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]error)
+	var plainArgs []string
+	for _, arg := range args {
+		switch {
+		case c.kustomize || isKustomizeDir(arg):
+			overlayErrors, err := validateKustomizeOverlay(arg, factory, policies)
+			if err != nil {
+				return nil, err
+			}
+			for path, errs := range overlayErrors {
+				result[path] = errs
+			}
+		case isHelmChartDir(arg):
+			chartErrors, err := validateHelmChart(arg, c.helmValuesFiles, c.helmSetValues, factory, policies)
+			if err != nil {
+				return nil, err
+			}
+			for path, errs := range chartErrors {
+				result[path] = errs
+			}
+		case isJsonnetFile(arg):
+			validateJsonnetFile(arg, c.jsonnetPath, factory, policies, result)
+		default:
+			plainArgs = append(plainArgs, arg)
+		}
+	}
+
+	var plainFiles []string
+	if len(plainArgs) > 0 {
+		files, err := utils.FindFiles(plainArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			if isJsonnetFile(path) {
+				validateJsonnetFile(path, c.jsonnetPath, factory, policies, result)
+				continue
+			}
+			plainFiles = append(plainFiles, path)
+			result[path] = ValidateFileWithPolicies(path, factory, policies)
+		}
+	}
+
+	if c.serverDryRun {
+		overrides := c.kubeConfigOverrides
+		if target.KubeContext != "" {
+			overrides.CurrentContext = target.KubeContext
+		}
+		dryRunClient, err := newServerDryRunClient(overrides)
+		if err != nil {
+			return nil, err
+		}
+		serverDryRunErrorsFlat(ctx, result, documentsForDryRun(plainFiles), dryRunClient)
+	}
+
+	return result, nil
+}
+
+// runMatrix validates args against every target in parallel (bounded by
+// maxConcurrentTargets) and merges the results into a single FilesErrors
+// keyed by target.Name, guarded by a mutex since targets run concurrently.
+func runMatrix(ctx context.Context, c *commandFlags, args []string, targets []validationTarget, policies *policySet) (FilesErrors, error) {
+	filesErrors := make(FilesErrors)
+	var mu sync.Mutex
+	var firstErr error
+
+	sem := make(chan struct{}, minInt(maxConcurrentTargets, runtime.GOMAXPROCS(0)))
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		target := target
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			targetErrors, err := validateAgainstTarget(ctx, c, args, target, policies)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("target %q: %w", target.Name, err)
+				}
+				return
+			}
+			for path, errs := range targetErrors {
+				filesErrors.set(path, target.Name, errs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return filesErrors, firstErr
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupTargetNames(t *testing.T) {
+	cases := []struct {
+		name    string
+		targets []validationTarget
+		want    []string
+	}{
+		{
+			name:    "unnamed targets get distinct synthetic names",
+			targets: []validationTarget{{}, {}},
+			want:    []string{"target-1", "target-2"},
+		},
+		{
+			name:    "duplicate explicit names are disambiguated",
+			targets: []validationTarget{{Name: "staging"}, {Name: "staging"}},
+			want:    []string{"staging", "staging#2"},
+		},
+		{
+			name:    "unique names are left untouched",
+			targets: []validationTarget{{Name: "staging"}, {Name: "prod"}},
+			want:    []string{"staging", "prod"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dedupTargetNames(tc.targets)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d targets, want %d", len(got), len(tc.want))
+			}
+			seen := make(map[string]bool, len(got))
+			for i, target := range got {
+				if target.Name != tc.want[i] {
+					t.Errorf("target %d: got name %q, want %q", i, target.Name, tc.want[i])
+				}
+				if seen[target.Name] {
+					t.Errorf("target %d: name %q collides with an earlier target", i, target.Name)
+				}
+				seen[target.Name] = true
+			}
+		})
+	}
+}
+
+// TestBuildTargets_MatrixFileDedup exercises the --matrix branch of
+// buildTargets end to end, guarding against the bug where targets loaded
+// from a matrix file bypassed name de-duplication entirely and could
+// silently overwrite each other's results in a FilesErrors.
+func TestBuildTargets_MatrixFileDedup(t *testing.T) {
+	matrixFile := filepath.Join(t.TempDir(), "matrix.yaml")
+	contents := `
+targets:
+  - name: staging
+    version: "1.30"
+  - name: staging
+    version: "1.29"
+  - version: "1.28"
+`
+	if err := os.WriteFile(matrixFile, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write matrix file: %v", err)
+	}
+
+	targets, err := buildTargets(&commandFlags{matrixFile: matrixFile})
+	if err != nil {
+		t.Fatalf("buildTargets returned error: %v", err)
+	}
+
+	seen := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if target.Name == "" {
+			t.Errorf("target %+v has an empty name", target)
+		}
+		if seen[target.Name] {
+			t.Errorf("target name %q collides with an earlier target", target.Name)
+		}
+		seen[target.Name] = true
+	}
+}
+
+// TestRunMatrix_ConcurrentMergeIsRaceFree calls the real runMatrix (not a
+// reimplementation of its locking) with several targets sharing one
+// manifest, so `go test -race` catches a regression that drops the mutex
+// guarding filesErrors.set in runMatrix's per-target goroutines.
+func TestRunMatrix_ConcurrentMergeIsRaceFree(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "configmap.yaml")
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0o644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	const targetCount = 8
+	targets := make([]validationTarget, targetCount)
+	for i := range targets {
+		targets[i] = validationTarget{Version: "1.27"}
+	}
+	targets = dedupTargetNames(targets)
+
+	c := &commandFlags{version: "1.27"}
+	filesErrors, err := runMatrix(context.Background(), c, []string{manifestPath}, targets, nil)
+	if err != nil {
+		t.Fatalf("runMatrix returned error: %v", err)
+	}
+
+	if len(filesErrors[manifestPath]) != targetCount {
+		t.Fatalf("got %d merged targets for %q, want %d", len(filesErrors[manifestPath]), manifestPath, targetCount)
+	}
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// policySet holds the ValidatingAdmissionPolicy + Binding objects loaded
+// from --policy-dir. A policy with no binding is inert in a real cluster, so
+// policiesFor requires a matching binding in addition to matchConstraints;
+// paramRef/namespaceSelector/objectSelector on the binding are not resolved,
+// since a local run has no params or namespace objects to evaluate them
+// against.
+type policySet struct {
+	policies []*admissionregistrationv1.ValidatingAdmissionPolicy
+	bindings []*admissionregistrationv1.ValidatingAdmissionPolicyBinding
+}
+
+// loadPolicyDir parses every .yaml/.yml file under dir as a
+// ValidatingAdmissionPolicy or ValidatingAdmissionPolicyBinding. An empty
+// dir is not an error; callers treat a nil *policySet as "no policies
+// configured".
+func loadPolicyDir(dir string) (*policySet, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	set := &policySet{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read policy file %q: %w", path, err)
+		}
+
+		var typeMeta struct {
+			Kind string `json:"kind"`
+		}
+		if err := yaml.Unmarshal(raw, &typeMeta); err != nil {
+			return fmt.Errorf("failed to parse policy file %q: %w", path, err)
+		}
+
+		switch typeMeta.Kind {
+		case "ValidatingAdmissionPolicy":
+			policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+			if err := yaml.Unmarshal(raw, policy); err != nil {
+				return fmt.Errorf("failed to parse ValidatingAdmissionPolicy %q: %w", path, err)
+			}
+			set.policies = append(set.policies, policy)
+		case "ValidatingAdmissionPolicyBinding":
+			binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+			if err := yaml.Unmarshal(raw, binding); err != nil {
+				return fmt.Errorf("failed to parse ValidatingAdmissionPolicyBinding %q: %w", path, err)
+			}
+			set.bindings = append(set.bindings, binding)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// policiesFor returns the policies whose matchConstraints select gvk and
+// that have at least one binding. A resource rule matches on its apiGroups
+// and apiVersions only: its resources list is not compared against gvk's
+// kind, and excludeResourceRules and operations are not evaluated, so a
+// policy for one kind also selects every other kind in the same group and
+// version. namespaceSelector/objectSelector are
+// intentionally not evaluated: this tool validates individual documents with
+// no cluster or namespace context to resolve them against.
+func (p *policySet) policiesFor(gvk schema.GroupVersionKind) []*admissionregistrationv1.ValidatingAdmissionPolicy {
+	var matched []*admissionregistrationv1.ValidatingAdmissionPolicy
+	for _, policy := range p.policies {
+		if policy.Spec.MatchConstraints == nil || !p.isBound(policy.Name) {
+			continue
+		}
+		for _, rule := range policy.Spec.MatchConstraints.ResourceRules {
+			if containsOrWildcard(rule.APIGroups, gvk.Group) && containsOrWildcard(rule.APIVersions, gvk.Version) {
+				matched = append(matched, policy)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// isBound reports whether policyName has at least one
+// ValidatingAdmissionPolicyBinding, without which a real cluster would never
+// enforce the policy.
+func (p *policySet) isBound(policyName string) bool {
+	for _, binding := range p.bindings {
+		if binding.Spec.PolicyName == policyName {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrWildcard(values []string, want string) bool {
+	for _, v := range values {
+		if v == "*" || v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// celPolicyEnv is shared by every compiled expression: all policies
+// evaluate against the same admission-style variables, so there is no need
+// to rebuild the environment per policy.
+var celPolicyEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("params", cel.DynType),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("namespaceObject", cel.DynType),
+	)
+})
+
+// unboundActivationVars are the admission-style variables this tool cannot
+// populate outside a live admission request: there is no previous object,
+// AdmissionRequest, bound params, or Namespace object to resolve them
+// against when validating a single document offline. They are marked
+// unknown (rather than bound to nil) in every CEL evaluation so an
+// expression that reads them, e.g. a `request.operation == "CREATE"` guard,
+// evaluates to unknown instead of hard-erroring and being misreported as a
+// policy denial; see unboundAttributePatterns.
+var unboundActivationVars = []string{"oldObject", "params", "request", "namespaceObject"}
+
+// unboundAttributePatterns builds the cel-go unknown-attribute patterns for
+// unboundActivationVars, for use with cel.PartialVars.
+func unboundAttributePatterns() []*interpreter.AttributePattern {
+	patterns := make([]*interpreter.AttributePattern, len(unboundActivationVars))
+	for i, name := range unboundActivationVars {
+		patterns[i] = cel.AttributePattern(name)
+	}
+	return patterns
+}
+
+// evaluatePolicies compiles and runs every policy selecting obj's GVK,
+// converting failed `spec.validations[]` expressions into *field.Error
+// entries so they render alongside schema errors in both output formats.
+func evaluatePolicies(policies *policySet, obj *unstructured.Unstructured) field.ErrorList {
+	if policies == nil {
+		return nil
+	}
+
+	env, err := celPolicyEnv()
+	if err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath(""), fmt.Errorf("failed to build CEL environment: %w", err))}
+	}
+
+	gvk := obj.GroupVersionKind()
+	activation := map[string]interface{}{
+		"object":          obj.Object,
+		"oldObject":       nil,
+		"params":          nil,
+		"request":         nil,
+		"namespaceObject": nil,
+	}
+
+	var errs field.ErrorList
+	for _, policy := range policies.policiesFor(gvk) {
+		for i, validation := range policy.Spec.Validations {
+			path := field.NewPath("spec").Child("validations").Index(i).Child("expression")
+			ast, issues := env.Compile(validation.Expression)
+			if issues != nil && issues.Err() != nil {
+				errs = append(errs, field.Invalid(path, validation.Expression,
+					fmt.Sprintf("policy %q: failed to compile: %v", policy.Name, issues.Err())))
+				continue
+			}
+
+			program, err := env.Program(ast, cel.EvalOptions(cel.OptPartialEval))
+			if err != nil {
+				errs = append(errs, field.Invalid(path, validation.Expression,
+					fmt.Sprintf("policy %q: failed to build program: %v", policy.Name, err)))
+				continue
+			}
+
+			vars, err := cel.PartialVars(activation, unboundAttributePatterns()...)
+			if err != nil {
+				errs = append(errs, field.Invalid(path, validation.Expression,
+					fmt.Sprintf("policy %q: failed to build partial activation: %v", policy.Name, err)))
+				continue
+			}
+
+			out, _, err := program.Eval(vars)
+			if err != nil {
+				errs = append(errs, field.Invalid(path, validation.Expression,
+					fmt.Sprintf("policy %q: expression errored: %v", policy.Name, err)))
+				continue
+			}
+			if types.IsUnknown(out) {
+				// The expression reads request/oldObject/namespaceObject/
+				// params, none of which this tool can populate locally;
+				// skip it rather than reporting a false policy denial.
+				continue
+			}
+			if pass, ok := out.Value().(bool); !ok || pass {
+				continue
+			}
+
+			message := validation.Message
+			if validation.MessageExpression != "" {
+				if rendered, err := evalMessageExpression(env, activation, validation.MessageExpression); err == nil {
+					message = rendered
+				}
+			}
+			if message == "" {
+				message = fmt.Sprintf("failed expression: %s", validation.Expression)
+			}
+			errs = append(errs, field.Invalid(field.NewPath("spec"), nil,
+				fmt.Sprintf("policy %q denied the request: %s", policy.Name, message)))
+		}
+	}
+	return errs
+}
+
+// evalMessageExpression compiles and runs a ValidatingAdmissionPolicy
+// validation's optional messageExpression, which must evaluate to a string.
+// Like evaluatePolicies, it treats request/oldObject/namespaceObject/params
+// as unknown rather than erroring on them, since this tool has none of them
+// available locally; the caller falls back to the static Message in that case.
+func evalMessageExpression(env *cel.Env, activation map[string]interface{}, expr string) (string, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return "", issues.Err()
+	}
+	program, err := env.Program(ast, cel.EvalOptions(cel.OptPartialEval))
+	if err != nil {
+		return "", err
+	}
+	vars, err := cel.PartialVars(activation, unboundAttributePatterns()...)
+	if err != nil {
+		return "", err
+	}
+	out, _, err := program.Eval(vars)
+	if err != nil {
+		return "", err
+	}
+	if types.IsUnknown(out) {
+		return "", fmt.Errorf("messageExpression depends on request/oldObject/namespaceObject/params, which are unavailable locally")
+	}
+	str, ok := out.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("messageExpression must evaluate to a string, got %T", out.Value())
+	}
+	return str, nil
+}
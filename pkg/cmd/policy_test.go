@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func matchConstraintsFor(group, version string) *admissionregistrationv1.MatchResources {
+	return &admissionregistrationv1.MatchResources{
+		ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{{
+			RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{group},
+					APIVersions: []string{version},
+				},
+			},
+		}},
+	}
+}
+
+func TestPoliciesFor_RequiresABinding(t *testing.T) {
+	bound := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	bound.Name = "bound-policy"
+	bound.Spec.MatchConstraints = matchConstraintsFor("apps", "v1")
+
+	unbound := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	unbound.Name = "unbound-policy"
+	unbound.Spec.MatchConstraints = matchConstraintsFor("apps", "v1")
+
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+	binding.Spec.PolicyName = "bound-policy"
+
+	set := &policySet{
+		policies: []*admissionregistrationv1.ValidatingAdmissionPolicy{bound, unbound},
+		bindings: []*admissionregistrationv1.ValidatingAdmissionPolicyBinding{binding},
+	}
+
+	matched := set.policiesFor(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if len(matched) != 1 || matched[0].Name != "bound-policy" {
+		t.Fatalf("got %v, want only the bound policy to match (an unbound policy is inert on a real cluster)", names(matched))
+	}
+}
+
+func TestPoliciesFor_IgnoresNonMatchingGVK(t *testing.T) {
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	policy.Name = "apps-only"
+	policy.Spec.MatchConstraints = matchConstraintsFor("apps", "v1")
+
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+	binding.Spec.PolicyName = "apps-only"
+
+	set := &policySet{
+		policies: []*admissionregistrationv1.ValidatingAdmissionPolicy{policy},
+		bindings: []*admissionregistrationv1.ValidatingAdmissionPolicyBinding{binding},
+	}
+
+	matched := set.policiesFor(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"})
+	if len(matched) != 0 {
+		t.Fatalf("got %v, want no match for a GVK outside the policy's matchConstraints", names(matched))
+	}
+}
+
+func TestEvaluatePolicies_SkipsExpressionsThatNeedLiveRequestContext(t *testing.T) {
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	policy.Name = "needs-request"
+	policy.Spec.MatchConstraints = matchConstraintsFor("apps", "v1")
+	policy.Spec.Validations = []admissionregistrationv1.Validation{
+		{Expression: "request.operation == 'CREATE'"},
+	}
+
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+	binding.Spec.PolicyName = "needs-request"
+
+	set := &policySet{
+		policies: []*admissionregistrationv1.ValidatingAdmissionPolicy{policy},
+		bindings: []*admissionregistrationv1.ValidatingAdmissionPolicyBinding{binding},
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": "demo"}}}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+
+	errs := evaluatePolicies(set, obj)
+	if len(errs) != 0 {
+		t.Fatalf("got %v, want no errors: an expression reading request.* can't be evaluated locally and should be skipped, not reported as a policy denial", errs)
+	}
+}
+
+func TestEvaluatePolicies_DeniesOnObjectOnlyExpression(t *testing.T) {
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	policy.Name = "name-must-be-good"
+	policy.Spec.MatchConstraints = matchConstraintsFor("apps", "v1")
+	policy.Spec.Validations = []admissionregistrationv1.Validation{
+		{Expression: "object.metadata.name == 'good'", Message: "name must be good"},
+	}
+
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+	binding.Spec.PolicyName = "name-must-be-good"
+
+	set := &policySet{
+		policies: []*admissionregistrationv1.ValidatingAdmissionPolicy{policy},
+		bindings: []*admissionregistrationv1.ValidatingAdmissionPolicyBinding{binding},
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": "bad"}}}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+
+	errs := evaluatePolicies(set, obj)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want exactly 1 denial for a failing object-only expression", len(errs))
+	}
+}
+
+func names(policies []*admissionregistrationv1.ValidatingAdmissionPolicy) []string {
+	out := make([]string, len(policies))
+	for i, p := range policies {
+		out[i] = p.Name
+	}
+	return out
+}
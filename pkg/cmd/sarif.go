@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/kubectl-validate/pkg/utils"
+)
+
+const sarifVersion = "2.1.0"
+const sarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+// sarifLog mirrors the subset of the SARIF v2.1.0 object model this tool
+// populates. Field names follow the spec exactly so the JSON tags match.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string `json:"id"`
+	ShortDescription struct {
+		Text string `json:"text"`
+	} `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// statusCausesFor extracts the per-field metav1.StatusCause entries behind
+// err, following the same precedence as errorToStatus: ValidateDocument's
+// dominant error shape is a *k8serrors.StatusError (from rest.BeforeCreate,
+// server-dry-run, and CEL policy denials via k8serrors.NewInvalid), which
+// does not unwrap to the original field.Error via errors.As, so its causes
+// must be read back out of ErrStatus.Details instead. A bare *field.Error is
+// handled too for completeness, and anything else yields no causes.
+func statusCausesFor(err error) []metav1.StatusCause {
+	var statusErr *k8serrors.StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.ErrStatus.Details != nil {
+			return statusErr.ErrStatus.Details.Causes
+		}
+		return nil
+	}
+	var fieldErr *field.Error
+	if errors.As(err, &fieldErr) {
+		return []metav1.StatusCause{{
+			Type:    metav1.CauseType(fieldErr.Type),
+			Message: fieldErr.ErrorBody(),
+			Field:   fieldErr.Field,
+		}}
+	}
+	return nil
+}
+
+// sarifRuleID derives a stable ruleId from a StatusCause's Type, e.g.
+// "FieldValueRequired" or "FieldValueInvalid", falling back to a generic id
+// for causes that carry no type.
+func sarifRuleID(cause metav1.StatusCause) string {
+	if cause.Type != "" {
+		return string(cause.Type)
+	}
+	return "ValidationError"
+}
+
+// printSarifErrors renders filesErrors as a SARIF v2.1.0 log, one result per
+// StatusCause behind a non-nil document error (falling back to one generic
+// result when an error carries no causes), with locations resolved to a
+// best-effort line/column by walking the source YAML for each cause's field
+// path. Results are grouped into one run per validation target; a run does
+// not record the name of its target, and runs are emitted in no fixed order.
+func printSarifErrors(filesErrors FilesErrors, outWriter io.Writer) error {
+	runsByTarget := map[string]*sarifRun{}
+	seenRulesByTarget := map[string]map[string]bool{}
+
+	runFor := func(target string) *sarifRun {
+		if run, ok := runsByTarget[target]; ok {
+			return run
+		}
+		run := &sarifRun{Tool: sarifTool{Driver: sarifDriver{
+			Name:           "kubectl-validate",
+			InformationURI: "https://github.com/kubernetes-sigs/kubectl-validate",
+		}}}
+		runsByTarget[target] = run
+		seenRulesByTarget[target] = map[string]bool{}
+		return run
+	}
+
+	for path, perTarget := range filesErrors {
+		fileBytes, _ := os.ReadFile(path)
+		documents, splitErr := utils.SplitYamlDocuments(fileBytes)
+		if splitErr != nil {
+			documents = nil
+		}
+		for target, errs := range perTarget {
+			run := runFor(target)
+			seenRules := seenRulesByTarget[target]
+			for i, err := range errs {
+				if err == nil {
+					continue
+				}
+				causes := statusCausesFor(err)
+				if len(causes) == 0 {
+					causes = []metav1.StatusCause{{}}
+				}
+				// A file can hold multiple "---"-separated documents;
+				// resolve each cause's location against the specific
+				// document that produced it, not the whole file, which
+				// yaml.Unmarshal would otherwise silently decode as just
+				// the first document in the stream.
+				docBytes := fileBytes
+				if i < len(documents) {
+					docBytes = documents[i]
+				}
+				for _, cause := range causes {
+					ruleID := sarifRuleID(cause)
+					if !seenRules[ruleID] {
+						seenRules[ruleID] = true
+						rule := sarifRule{ID: ruleID}
+						rule.ShortDescription.Text = ruleID
+						run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+					}
+
+					message := cause.Message
+					if message == "" {
+						message = err.Error()
+					}
+					line, col := locateFieldError(docBytes, cause.Field)
+					run.Results = append(run.Results, sarifResult{
+						RuleID:  ruleID,
+						Level:   "error",
+						Message: sarifMessage{Text: message},
+						Locations: []sarifLocation{{
+							PhysicalLocation: sarifPhysicalLocation{
+								ArtifactLocation: sarifArtifactLocation{URI: path},
+								Region:           sarifRegion{StartLine: line, StartColumn: col},
+							},
+						}},
+					})
+				}
+			}
+		}
+	}
+
+	log := sarifLog{Schema: sarifSchema, Version: sarifVersion}
+	for _, run := range runsByTarget {
+		log.Runs = append(log.Runs, *run)
+	}
+	data, err := json.MarshalIndent(log, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to render results into SARIF: %w", err)
+	}
+	fmt.Fprintln(outWriter, string(data))
+	return nil
+}
+
+// printJUnitErrors renders filesErrors as a JUnit XML report with one
+// <testsuite> per file and validation target, named "<file> (<target>)", and
+// one <testcase> per document, so the tool can be
+// consumed directly by CI systems that understand JUnit.
+func printJUnitErrors(filesErrors FilesErrors, outWriter io.Writer) error {
+	type failure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+	type testcase struct {
+		Name    string   `xml:"name,attr"`
+		Failure *failure `xml:"failure,omitempty"`
+	}
+	type testsuite struct {
+		Name      string     `xml:"name,attr"`
+		Tests     int        `xml:"tests,attr"`
+		Failures  int        `xml:"failures,attr"`
+		Testcases []testcase `xml:"testcase"`
+	}
+	type testsuites struct {
+		XMLName xml.Name    `xml:"testsuites"`
+		Suites  []testsuite `xml:"testsuite"`
+	}
+
+	var suites testsuites
+	for path, perTarget := range filesErrors {
+		for target, errs := range perTarget {
+			suite := testsuite{Name: fmt.Sprintf("%s (%s)", path, target)}
+			for i, err := range errs {
+				tc := testcase{Name: fmt.Sprintf("%s#%d", path, i)}
+				suite.Tests++
+				if err != nil {
+					suite.Failures++
+					tc.Failure = &failure{Message: err.Error(), Text: err.Error()}
+				}
+				suite.Testcases = append(suite.Testcases, tc)
+			}
+			suites.Suites = append(suites.Suites, suite)
+		}
+	}
+
+	fmt.Fprint(outWriter, xml.Header)
+	enc := xml.NewEncoder(outWriter)
+	enc.Indent("", "    ")
+	if err := enc.Encode(suites); err != nil {
+		return fmt.Errorf("failed to render results into JUnit XML: %w", err)
+	}
+	fmt.Fprintln(outWriter)
+	return nil
+}
+
+// fieldPathSegment matches a single path component, e.g. "spec" or
+// "containers[0]", as produced by field.Path.String().
+var fieldPathSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+
+// locateFieldError walks fileBytes' YAML AST to find the line/column of the
+// node addressed by fieldPath (a field.Path.String()-shaped dotted path, as
+// carried on a field.Error or a StatusCause's Field). It falls back to the
+// start of the document when no path is given or the document cannot be
+// parsed, and to the deepest node it could reach when only part of the path
+// resolves.
+func locateFieldError(fileBytes []byte, fieldPath string) (line int, col int) {
+	if fieldPath == "" || len(fileBytes) == 0 {
+		return 1, 1
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(fileBytes, &doc); err != nil || len(doc.Content) == 0 {
+		return 1, 1
+	}
+
+	node := doc.Content[0]
+	for _, part := range strings.Split(fieldPath, ".") {
+		m := fieldPathSegment.FindStringSubmatch(part)
+		if m == nil {
+			return node.Line, node.Column
+		}
+		if name := m[1]; name != "" {
+			next := lookupMapValue(node, name)
+			if next == nil {
+				return node.Line, node.Column
+			}
+			node = next
+		}
+		for _, idx := range regexp.MustCompile(`\[(\d+)\]`).FindAllStringSubmatch(m[2], -1) {
+			i, _ := strconv.Atoi(idx[1])
+			if node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				return node.Line, node.Column
+			}
+			node = node.Content[i]
+		}
+	}
+	return node.Line, node.Column
+}
+
+// lookupMapValue returns the value node for key in a YAML mapping node, or
+// nil if node is not a mapping or does not contain key.
+func lookupMapValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/kubectl-validate/pkg/utils"
+	"sigs.k8s.io/yaml"
+)
+
+// fieldManager identifies this tool's writes to the API server so that a
+// subsequent real `kubectl apply` does not conflict with dry-run field
+// ownership.
+const fieldManager = "kubectl-validate"
+
+// serverDryRunClient resolves GVKs to the dynamic client resource interface
+// needed to submit a dry-run create/patch, built lazily from the same
+// kubeconfig overrides used for schema discovery.
+type serverDryRunClient struct {
+	dynamicClient dynamic.Interface
+	mapper        *restmapper.DeferredDiscoveryRESTMapper
+}
+
+func newServerDryRunClient(overrides clientcmd.ConfigOverrides) (*serverDryRunClient, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig for --server-dry-run: %w", err)
+	}
+
+	disco, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	return &serverDryRunClient{
+		dynamicClient: dyn,
+		mapper:        restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(disco)),
+	}, nil
+}
+
+// apply submits obj to the connected cluster with DryRun: All, creating it
+// if it does not already exist and falling back to a server-side-apply
+// PATCH otherwise, so that both object validation and admission webhook /
+// ValidatingAdmissionPolicy rejection paths are exercised.
+func (c *serverDryRunClient) apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("failed to map %v to a resource: %w", gvk, err)
+	}
+
+	var resourceClient dynamic.ResourceInterface
+	if mapping.Scope.Name() == "namespace" {
+		resourceClient = c.dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	} else {
+		resourceClient = c.dynamicClient.Resource(mapping.Resource)
+	}
+
+	_, err = resourceClient.Create(ctx, obj, metav1.CreateOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: fieldManager,
+	})
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	data, marshalErr := yaml.Marshal(obj.Object)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	_, err = resourceClient.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: fieldManager,
+		Force:        boolPtr(true),
+	})
+	return err
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// documentsForDryRun re-splits each already-validated file into its
+// constituent YAML documents so serverDryRunErrorsFlat can submit the exact
+// bytes that were locally validated, indexed the same way as FilesErrors.
+func documentsForDryRun(paths []string) map[string][][]byte {
+	documentsByPath := make(map[string][][]byte, len(paths))
+	for _, path := range paths {
+		fileBytes, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if !utils.IsYaml(path) {
+			// ValidateFileWithPolicies validates a non-YAML file as one document.
+			documentsByPath[path] = [][]byte{fileBytes}
+			continue
+		}
+		documents, err := utils.SplitYamlDocuments(fileBytes)
+		if err != nil {
+			continue
+		}
+		documentsByPath[path] = documents
+	}
+	return documentsByPath
+}
+
+// serverDryRunErrorsFlat runs apply in dry-run mode for every successfully
+// locally-validated document in filesErrors that documentsByPath holds the
+// bytes of, merging any returned *k8serrors.StatusError into that document's
+// error slot so static and admission-time errors are reported side by side.
+// Paths with no entry in documentsByPath (such as documents rendered from a
+// Helm chart, a kustomize overlay or a Jsonnet file) are not submitted.
+func serverDryRunErrorsFlat(ctx context.Context, filesErrors map[string][]error, documentsByPath map[string][][]byte, client *serverDryRunClient) {
+	for path, errs := range filesErrors {
+		documents := documentsByPath[path]
+		for i, err := range errs {
+			if err != nil || i >= len(documents) || utils.IsEmptyYamlDocument(documents[i]) {
+				// Only exercise the server for documents that passed local
+				// validation; an empty YAML doc (e.g. a trailing "---") has
+				// nothing to submit and would otherwise fail RESTMapping on
+				// its empty GVK.
+				continue
+			}
+			obj := &unstructured.Unstructured{}
+			if unmarshalErr := yaml.Unmarshal(documents[i], &obj.Object); unmarshalErr != nil {
+				continue
+			}
+			if dryRunErr := client.apply(ctx, obj); dryRunErr != nil {
+				var statusErr *k8serrors.StatusError
+				if errors.As(dryRunErr, &statusErr) {
+					filesErrors[path][i] = statusErr
+				} else {
+					filesErrors[path][i] = dryRunErr
+				}
+			}
+		}
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"sigs.k8s.io/kubectl-validate/pkg/openapiclient"
 	"sigs.k8s.io/kubectl-validate/pkg/utils"
 	"sigs.k8s.io/kubectl-validate/pkg/validatorfactory"
 	"sigs.k8s.io/yaml"
@@ -36,6 +35,8 @@ type OutputFormat string
 const (
 	OutputHuman OutputFormat = "human"
 	OutputJSON  OutputFormat = "json"
+	OutputSarif OutputFormat = "sarif"
+	OutputJUnit OutputFormat = "junit"
 )
 
 // String is used both by fmt.Print and by Cobra in help text
@@ -46,11 +47,11 @@ func (e *OutputFormat) String() string {
 // Set must have pointer receiver so it doesn't change the value of a copy
 func (e *OutputFormat) Set(v string) error {
 	switch v {
-	case "human", "json":
+	case "human", "json", "sarif", "junit":
 		*e = OutputFormat(v)
 		return nil
 	default:
-		return fmt.Errorf(`must be one of "human", or "json"`)
+		return fmt.Errorf(`must be one of "human", "json", "sarif", or "junit"`)
 	}
 }
 
@@ -59,22 +60,45 @@ func (e *OutputFormat) Type() string {
 	return "OutputFormat"
 }
 
-// A type to store list of errors for each file
-type FilesErrors map[string][]error
+// A type to store list of errors for each file, per validation target. A
+// single-target run (the common case) has exactly one key per path, named
+// "default" unless a --matrix file gives the target another name.
+type FilesErrors map[string]map[string][]error
 
-// Returns true if there is at least a file containing a document with error
+// set records errs for path under the given target, initializing the
+// per-path map on first use.
+func (fe FilesErrors) set(path, target string, errs []error) {
+	if fe[path] == nil {
+		fe[path] = make(map[string][]error)
+	}
+	fe[path][target] = errs
+}
+
+// Returns true if there is at least a file/target cell containing a document with error
 func (fe FilesErrors) hasError() bool {
 	for path := range fe {
-		if fe.hasFileError(path) {
-			return true
+		for target := range fe[path] {
+			if fe.hasCellError(path, target) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-// Returns true if at least a document in this file has error
+// Returns true if at least a document in this file, for any target, has error
 func (fe FilesErrors) hasFileError(path string) bool {
-	for _, err := range fe[path] {
+	for target := range fe[path] {
+		if fe.hasCellError(path, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if at least a document in this file, for this target, has error
+func (fe FilesErrors) hasCellError(path, target string) bool {
+	for _, err := range fe[path][target] {
 		if err != nil {
 			return true
 		}
@@ -85,10 +109,20 @@ func (fe FilesErrors) hasFileError(path string) bool {
 type commandFlags struct {
 	kubeConfigOverrides clientcmd.ConfigOverrides
 	version             string
+	versions            []string
+	kubeContexts        []string
 	localSchemasDir     string
 	localCRDsDir        string
+	localCRDsDirs       []string
 	schemaPatchesDir    string
+	matrixFile          string
 	outputFormat        OutputFormat
+	helmValuesFiles     []string
+	helmSetValues       []string
+	kustomize           bool
+	serverDryRun        bool
+	policyDir           string
+	jsonnetPath         []string
 }
 
 func NewRootCommand() *cobra.Command {
@@ -105,10 +139,20 @@ func NewRootCommand() *cobra.Command {
 		SilenceUsage: true,
 	}
 	res.Flags().StringVarP(&invoked.version, "version", "", "", "Kubernetes version to validate native resources against. Required if not connected directly to cluster")
+	res.Flags().StringArrayVarP(&invoked.versions, "versions", "", nil, "Repeat --versions to validate against a matrix of Kubernetes versions. Paired positionally with --kube-contexts/--local-crds-matrix when those are also repeated; use --matrix for full control over pairing.")
+	res.Flags().StringArrayVarP(&invoked.kubeContexts, "kube-contexts", "", nil, "Repeat to validate against a matrix of kube contexts, in addition to --versions. See --matrix for full control over pairing. Distinct from the single --kube-context override flag, which still applies to any target that names no context.")
 	res.Flags().StringVarP(&invoked.localSchemasDir, "local-schemas", "", "", "--local-schemas=./path/to/schemas/dir. Path to a directory with format: /apis/<group>/<version>.json for each group-version's schema.")
 	res.Flags().StringVarP(&invoked.localCRDsDir, "local-crds", "", "", "--local-crds=./path/to/crds/dir. Path to a directory containing .yaml or .yml files for CRD definitions.")
+	res.Flags().StringArrayVarP(&invoked.localCRDsDirs, "local-crds-matrix", "", nil, "Repeat to validate against a matrix of local CRD directories, in addition to --versions.")
 	res.Flags().StringVarP(&invoked.schemaPatchesDir, "schema-patches", "", "", "Path to a directory with format: /apis/<group>/<version>.json for each group-version's schema you wish to jsonpatch to the groupversion's final schema. Patches only apply if the schema exists")
-	res.Flags().VarP(&invoked.outputFormat, "output", "o", "Output format. Choice of: \"human\" or \"json\"")
+	res.Flags().StringVarP(&invoked.matrixFile, "matrix", "", "", "Path to a YAML file listing named validation targets (version/kubeContext/localCRDs) to validate every input against, e.g. to certify manifests against staging and prod in one run. Overrides --versions/--kube-contexts/--local-crds-matrix.")
+	res.Flags().VarP(&invoked.outputFormat, "output", "o", "Output format. Choice of: \"human\", \"json\", \"sarif\", or \"junit\"")
+	res.Flags().StringArrayVarP(&invoked.helmValuesFiles, "values", "f", nil, "Helm chart values file to apply, in the style of 'helm template -f'. May be repeated; only used when an argument is a Helm chart directory.")
+	res.Flags().StringArrayVarP(&invoked.helmSetValues, "set", "", nil, "Helm chart value to set on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2), in the style of 'helm template --set'. Only used when an argument is a Helm chart directory.")
+	res.Flags().BoolVarP(&invoked.kustomize, "kustomize", "k", false, "Treat every argument as a kustomize overlay directory, build it with kustomize, and validate the emitted resources, in the style of 'kubectl apply -k'.")
+	res.Flags().BoolVarP(&invoked.serverDryRun, "server-dry-run", "", false, "After local validation succeeds for a document, also submit it to the connected cluster with a dry-run create/apply so admission webhook and ValidatingAdmissionPolicy rejections are surfaced alongside schema errors. Only plain YAML/JSON file inputs are submitted; documents rendered from Helm charts, kustomize overlays or Jsonnet files are validated locally only. Requires a reachable cluster.")
+	res.Flags().StringVarP(&invoked.policyDir, "policy-dir", "", "", "Path to a directory of ValidatingAdmissionPolicy/ValidatingAdmissionPolicyBinding YAML files to evaluate locally via CEL, in addition to structural/CRD schema validation. Expressions are evaluated against the manifest as `object`; `request`, `oldObject`, `namespaceObject`, and `params` are unavailable outside a live admission request, so validations that depend on them are skipped rather than evaluated.")
+	res.Flags().StringArrayVarP(&invoked.jsonnetPath, "jpath", "J", nil, "Additional library search path for .jsonnet/.libsonnet inputs. May be repeated.")
 	clientcmd.BindOverrideFlags(&invoked.kubeConfigOverrides, res.Flags(), clientcmd.RecommendedConfigOverrideFlags("kube-"))
 	return res
 }
@@ -145,61 +189,38 @@ func errorToStatus(err error) metav1.Status {
 }
 
 func (c *commandFlags) Run(cmd *cobra.Command, args []string) error {
-	// tool fetches openapi in the following priority order:
-	factory, err := validatorfactory.New(
-		openapiclient.NewOverlay(
-			// apply user defined patches on top of the final schema
-			openapiclient.PatchLoaderFromDirectory(nil, c.schemaPatchesDir),
-			openapiclient.NewComposite(
-				// consult local OpenAPI
-				openapiclient.NewLocalSchemaFiles(nil, c.localSchemasDir),
-				// consult local CRDs
-				openapiclient.NewLocalCRDFiles(nil, c.localCRDsDir),
-				openapiclient.NewOverlay(
-					// apply schema extensions to builtins
-					//!TODO: if kubeconfig is used, these patches may not be
-					// compatible. Use active version of kubernetes to decide
-					// patch to use if connected to cluster.
-					openapiclient.HardcodedPatchLoader(c.version),
-					// try cluster for schemas first, if they are not available
-					// then fallback to hardcoded or builtin schemas
-					openapiclient.NewFallback(
-						// contact connected cluster for any schemas. (should this be opt-in?)
-						openapiclient.NewKubeConfig(c.kubeConfigOverrides),
-						// try hardcoded builtins first, if they are not available
-						// fall back to GitHub builtins
-						openapiclient.NewFallback(
-							// schemas for known k8s versions are scraped from GH and placed here
-							openapiclient.NewHardcodedBuiltins(c.version),
-							// check github for builtins not hardcoded.
-							// subject to rate limiting. should use a diskcache
-							// since etag requests are not limited
-							openapiclient.NewGitHubBuiltins(c.version),
-						)),
-				),
-			),
-		),
-	)
+	targets, err := buildTargets(c)
 	if err != nil {
 		return err
 	}
 
-	files, err := utils.FindFiles(args...)
+	policies, err := loadPolicyDir(c.policyDir)
 	if err != nil {
 		return err
 	}
 
-	filesErrors := make(FilesErrors)
-	for _, path := range files {
-		errors := ValidateFile(path, factory)
-		filesErrors[path] = errors
+	// Every input is validated against every target (one ValidatorFactory
+	// each), run concurrently with a bounded worker pool so a large
+	// --matrix doesn't open unbounded connections to every cluster at once.
+	filesErrors, err := runMatrix(cmd.Context(), c, args, targets, policies)
+	if err != nil {
+		return err
 	}
 
-	if c.outputFormat == OutputHuman {
-		if err := printHumanErrors(filesErrors, cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
+	switch c.outputFormat {
+	case OutputHuman:
+		if err := printHumanErrors(filesErrors, targets, cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
 			return err
 		}
-	} else {
+	case OutputSarif:
+		if err := printSarifErrors(filesErrors, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+	case OutputJUnit:
+		if err := printJUnitErrors(filesErrors, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+	default:
 		if err := printJsonErrors(filesErrors, cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
 			return err
 		}
@@ -213,6 +234,13 @@ func (c *commandFlags) Run(cmd *cobra.Command, args []string) error {
 }
 
 func ValidateFile(filePath string, resolver *validatorfactory.ValidatorFactory) []error {
+	return ValidateFileWithPolicies(filePath, resolver, nil)
+}
+
+// ValidateFileWithPolicies behaves like ValidateFile, additionally
+// evaluating policies (when non-nil) against every document after it passes
+// structural/CRD schema validation.
+func ValidateFileWithPolicies(filePath string, resolver *validatorfactory.ValidatorFactory, policies *policySet) []error {
 	fileBytes, err := os.ReadFile(filePath)
 	if err != nil {
 		return []error{fmt.Errorf("error reading file: %w", err)}
@@ -227,18 +255,25 @@ func ValidateFile(filePath string, resolver *validatorfactory.ValidatorFactory)
 			if utils.IsEmptyYamlDocument(document) {
 				errs = append(errs, nil)
 			} else {
-				errs = append(errs, ValidateDocument(document, resolver))
+				errs = append(errs, ValidateDocumentWithPolicies(document, resolver, policies))
 			}
 		}
 		return errs
 	} else {
 		return []error{
-			ValidateDocument(fileBytes, resolver),
+			ValidateDocumentWithPolicies(fileBytes, resolver, policies),
 		}
 	}
 }
 
 func ValidateDocument(document []byte, resolver *validatorfactory.ValidatorFactory) error {
+	return ValidateDocumentWithPolicies(document, resolver, nil)
+}
+
+// ValidateDocumentWithPolicies behaves like ValidateDocument, additionally
+// evaluating policies (when non-nil) against the decoded object after it
+// passes structural/CRD schema validation.
+func ValidateDocumentWithPolicies(document []byte, resolver *validatorfactory.ValidatorFactory, policies *policySet) error {
 	metadata := metav1.TypeMeta{}
 	if err := yaml.Unmarshal(document, &metadata); err != nil {
 		return fmt.Errorf("failed to parse yaml: %w", err)
@@ -317,31 +352,77 @@ func ValidateDocument(document []byte, resolver *validatorfactory.ValidatorFacto
 	}, nil, nil)
 
 	rest.FillObjectMetaSystemFields(obj)
-	return rest.BeforeCreate(strat, request.WithNamespace(context.TODO(), obj.GetNamespace()), obj)
+	if err := rest.BeforeCreate(strat, request.WithNamespace(context.TODO(), obj.GetNamespace()), obj); err != nil {
+		return err
+	}
+
+	if policyErrs := evaluatePolicies(policies, obj); len(policyErrs) > 0 {
+		return k8serrors.NewInvalid(gvk.GroupKind(), obj.GetName(), policyErrs)
+	}
+	return nil
 }
 
-func printHumanErrors(filesErrors FilesErrors, outWriter io.Writer, errWriter io.Writer) error {
-	for path, errs := range filesErrors {
-		fmt.Fprintf(outWriter, "\n\033[1m%v\033[0m...", path)
-		if filesErrors.hasFileError(path) {
-			fmt.Fprintln(outWriter, "\033[31mERROR\033[0m")
-			for _, err := range errs {
+func printHumanErrors(filesErrors FilesErrors, targets []validationTarget, outWriter io.Writer, errWriter io.Writer) error {
+	if len(targets) <= 1 {
+		name := "default"
+		if len(targets) == 1 {
+			name = targets[0].Name
+		}
+		for path, perTarget := range filesErrors {
+			fmt.Fprintf(outWriter, "\n\033[1m%v\033[0m...", path)
+			if filesErrors.hasFileError(path) {
+				fmt.Fprintln(outWriter, "\033[31mERROR\033[0m")
+				for _, err := range perTarget[name] {
+					if err != nil {
+						fmt.Fprintln(errWriter, err.Error())
+					}
+				}
+			} else {
+				fmt.Fprintln(outWriter, "\033[32mOK\033[0m")
+			}
+		}
+		return nil
+	}
+
+	// Multi-target: print a compact file x target grid of OK/ERROR cells,
+	// then the error bodies for any cell that failed.
+	fmt.Fprint(outWriter, "\n\033[1mFILE\033[0m")
+	for _, target := range targets {
+		fmt.Fprintf(outWriter, "\t\033[1m%v\033[0m", target.Name)
+	}
+	fmt.Fprintln(outWriter)
+	for path := range filesErrors {
+		fmt.Fprint(outWriter, path)
+		for _, target := range targets {
+			if filesErrors.hasCellError(path, target.Name) {
+				fmt.Fprint(outWriter, "\t\033[31mERROR\033[0m")
+			} else {
+				fmt.Fprint(outWriter, "\t\033[32mOK\033[0m")
+			}
+		}
+		fmt.Fprintln(outWriter)
+	}
+
+	for path, perTarget := range filesErrors {
+		for _, target := range targets {
+			for _, err := range perTarget[target.Name] {
 				if err != nil {
-					fmt.Fprintln(errWriter, err.Error())
+					fmt.Fprintf(errWriter, "%s [%s]: %s\n", path, target.Name, err.Error())
 				}
 			}
-		} else {
-			fmt.Fprintln(outWriter, "\033[32mOK\033[0m")
 		}
 	}
 	return nil
 }
 
 func printJsonErrors(filesErrors FilesErrors, outWriter io.Writer, errWriter io.Writer) error {
-	res := map[string][]metav1.Status{}
-	for path, errs := range filesErrors {
-		for _, err := range errs {
-			res[path] = append(res[path], errorToStatus(err))
+	res := map[string]map[string][]metav1.Status{}
+	for path, perTarget := range filesErrors {
+		res[path] = map[string][]metav1.Status{}
+		for target, errs := range perTarget {
+			for _, err := range errs {
+				res[path][target] = append(res[path][target], errorToStatus(err))
+			}
 		}
 	}
 	data, e := json.MarshalIndent(res, "", "    ")